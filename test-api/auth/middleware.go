@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/webbdan/test-automation/test-api/respond"
+	"github.com/webbdan/test-automation/test-api/store"
+)
+
+type contextKey string
+
+const userContextKey contextKey = "auth.user"
+
+// UserFromContext returns the authenticated user stored in ctx by
+// RequireAuth, if any.
+func UserFromContext(ctx context.Context) (store.User, bool) {
+	user, ok := ctx.Value(userContextKey).(store.User)
+	return user, ok
+}
+
+// Middleware authenticates requests via session cookie, enforces
+// role-based authorization, and records an audit entry for every request
+// it gates.
+type Middleware struct {
+	users   store.UserStore
+	audit   store.AuditStore
+	session *SessionStore
+}
+
+// NewMiddleware returns a Middleware backed by the given stores.
+func NewMiddleware(users store.UserStore, audit store.AuditStore, sessions *SessionStore) *Middleware {
+	return &Middleware{users: users, audit: audit, session: sessions}
+}
+
+// RequireAuth rejects requests without a valid session, and otherwise
+// attaches the authenticated store.User to the request context.
+func (m *Middleware) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := m.session.Lookup(r)
+		if !ok {
+			m.recordAudit(r, 0, "unauthorized")
+			respond.Err(w, http.StatusUnauthorized, respond.CodeUnauthorized, "unauthorized")
+			return
+		}
+
+		user, err := m.users.Get(r.Context(), userID)
+		if err != nil {
+			m.recordAudit(r, userID, "unauthorized")
+			respond.Err(w, http.StatusUnauthorized, respond.CodeUnauthorized, "unauthorized")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userContextKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireAdmin rejects requests from non-admin users. It must be chained
+// after RequireAuth.
+func (m *Middleware) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := UserFromContext(r.Context())
+		if !ok || user.Role != store.RoleAdmin {
+			m.recordAudit(r, user.ID, "forbidden")
+			respond.Err(w, http.StatusForbidden, respond.CodeForbidden, "forbidden")
+			return
+		}
+		m.recordAudit(r, user.ID, "allowed")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireSelfOrAdmin rejects requests unless the authenticated user is an
+// admin or is acting on their own record, as identified by the {id} path
+// value. It must be chained after RequireAuth.
+func (m *Middleware) RequireSelfOrAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := UserFromContext(r.Context())
+		if !ok {
+			respond.Err(w, http.StatusForbidden, respond.CodeForbidden, "forbidden")
+			return
+		}
+
+		targetID := r.PathValue("id")
+		if user.Role != store.RoleAdmin && targetID != strconv.Itoa(user.ID) {
+			m.recordAudit(r, user.ID, "forbidden")
+			respond.Err(w, http.StatusForbidden, respond.CodeForbidden, "forbidden")
+			return
+		}
+		m.recordAudit(r, user.ID, "allowed")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Audited records an "allowed" audit entry for every request that reaches
+// it. It must be chained after RequireAuth. Use it on routes that have no
+// extra role check to pass (unlike RequireAdmin/RequireSelfOrAdmin, which
+// record their own allowed/forbidden outcome as part of that check), so
+// every mutating route still ends up in the audit log.
+func (m *Middleware) Audited(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := UserFromContext(r.Context())
+		m.recordAudit(r, user.ID, "allowed")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *Middleware) recordAudit(r *http.Request, userID int, outcome string) {
+	m.audit.Record(r.Context(), store.AuditEntry{
+		Timestamp: time.Now(),
+		UserID:    userID,
+		IP:        clientIP(r),
+		Action:    r.Method + " " + r.URL.Path,
+		Outcome:   outcome,
+	})
+}
+
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
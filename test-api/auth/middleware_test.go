@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/webbdan/test-automation/test-api/store"
+)
+
+// TestAuditedRecordsAllowedEntry guards against routes with no extra
+// authorization tier (like POST /users/) silently skipping the audit log.
+func TestAuditedRecordsAllowedEntry(t *testing.T) {
+	users := store.NewMemoryStore()
+	caller, err := users.Create(context.Background(), store.User{Name: "Caller", Email: "caller@example.com", Role: store.RoleUser})
+	if err != nil {
+		t.Fatalf("seeding caller: %v", err)
+	}
+
+	audit := store.NewMemoryAuditStore()
+	mw := NewMiddleware(users, audit, NewSessionStore())
+
+	called := false
+	handler := mw.Audited(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/users/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), userContextKey, caller))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if !called {
+		t.Fatal("Audited must call through to the wrapped handler")
+	}
+
+	entries, err := audit.List(req.Context())
+	if err != nil {
+		t.Fatalf("listing audit entries: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected exactly one audit entry, got %d", len(entries))
+	}
+	if entries[0].UserID != caller.ID || entries[0].Outcome != "allowed" {
+		t.Fatalf("unexpected audit entry: %+v", entries[0])
+	}
+}
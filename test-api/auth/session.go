@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const sessionCookieName = "session"
+const sessionTTL = 24 * time.Hour
+
+// Session ties an opaque token to the authenticated user it was issued for.
+type Session struct {
+	UserID    int
+	ExpiresAt time.Time
+}
+
+// SessionStore tracks issued session tokens in memory. Sessions do not need
+// to survive a restart: a dropped session simply forces re-authentication.
+type SessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewSessionStore returns an empty SessionStore ready for use.
+func NewSessionStore() *SessionStore {
+	return &SessionStore{sessions: make(map[string]Session)}
+}
+
+// Issue creates a new session for userID and sets it as a cookie on w.
+func (s *SessionStore) Issue(w http.ResponseWriter, userID int) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.sessions[token] = Session{UserID: userID, ExpiresAt: time.Now().Add(sessionTTL)}
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Expires:  time.Now().Add(sessionTTL),
+	})
+	return nil
+}
+
+// Lookup returns the userID associated with the request's session cookie.
+func (s *SessionStore) Lookup(r *http.Request) (int, bool) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return 0, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[cookie.Value]
+	if !ok || time.Now().After(session.ExpiresAt) {
+		return 0, false
+	}
+	return session.UserID, true
+}
+
+// Revoke deletes the session identified by the request's cookie, if any.
+func (s *SessionStore) Revoke(r *http.Request) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.sessions, cookie.Value)
+	s.mu.Unlock()
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,356 @@
+// Package auth implements passwordless WebAuthn registration and login,
+// session issuance, and the authorization middleware gating mutating
+// routes.
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"github.com/webbdan/test-automation/test-api/respond"
+	"github.com/webbdan/test-automation/test-api/store"
+)
+
+// webauthnUser adapts a store.User plus its registered credentials to the
+// webauthn.User interface required by the webauthn library.
+type webauthnUser struct {
+	user        store.User
+	credentials []webauthn.Credential
+}
+
+func (u *webauthnUser) WebAuthnID() []byte {
+	return []byte(u.user.Email)
+}
+
+func (u *webauthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webauthnUser) WebAuthnDisplayName() string {
+	return u.user.Name
+}
+
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+// WebAuthnIcon satisfies webauthn.User; this server has no per-user icon to
+// offer, and the field is deprecated in the WebAuthn spec itself.
+func (u *webauthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+// CredentialStore holds the WebAuthn credentials registered per user. It is
+// kept in memory; production deployments would persist this alongside the
+// configured UserStore backend.
+type CredentialStore struct {
+	mu          sync.Mutex
+	byUserID    map[int][]webauthn.Credential
+	sessionData map[string]webauthn.SessionData
+}
+
+// NewCredentialStore returns an empty CredentialStore ready for use.
+func NewCredentialStore() *CredentialStore {
+	return &CredentialStore{
+		byUserID:    make(map[int][]webauthn.Credential),
+		sessionData: make(map[string]webauthn.SessionData),
+	}
+}
+
+func (c *CredentialStore) add(userID int, cred webauthn.Credential) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byUserID[userID] = append(c.byUserID[userID], cred)
+}
+
+// empty reports whether no credential has ever been registered for any
+// user, meaning the server has no way in yet.
+func (c *CredentialStore) empty() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, creds := range c.byUserID {
+		if len(creds) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *CredentialStore) get(userID int) []webauthn.Credential {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.byUserID[userID]
+}
+
+func (c *CredentialStore) putSession(key string, data webauthn.SessionData) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessionData[key] = data
+}
+
+func (c *CredentialStore) takeSession(key string) (webauthn.SessionData, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.sessionData[key]
+	delete(c.sessionData, key)
+	return data, ok
+}
+
+// Service wires together the WebAuthn relying party, credential storage,
+// session issuance, and the backing UserStore.
+type Service struct {
+	webauthn       *webauthn.WebAuthn
+	users          store.UserStore
+	credentials    *CredentialStore
+	sessions       *SessionStore
+	bootstrapToken string
+}
+
+// Config describes the WebAuthn relying party this server presents itself
+// as; RPOrigin must match the scheme+host the browser sees. BootstrapToken
+// gates the one-time bootstrap exception in authorizeCredentialTarget; an
+// empty BootstrapToken disables that exception entirely.
+type Config struct {
+	RPDisplayName  string
+	RPID           string
+	RPOrigin       string
+	BootstrapToken string
+}
+
+// NewService constructs a Service from the given relying-party config and
+// backing stores.
+func NewService(cfg Config, users store.UserStore, sessions *SessionStore) (*Service, error) {
+	w, err := webauthn.New(&webauthn.Config{
+		RPDisplayName: cfg.RPDisplayName,
+		RPID:          cfg.RPID,
+		RPOrigins:     []string{cfg.RPOrigin},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		webauthn:       w,
+		users:          users,
+		credentials:    NewCredentialStore(),
+		sessions:       sessions,
+		bootstrapToken: cfg.BootstrapToken,
+	}, nil
+}
+
+// BeginRegistration starts WebAuthn credential registration for an existing
+// user identified by id in the request body. The caller must already hold a
+// session for that user, or be an admin registering a credential on behalf
+// of another account.
+func (s *Service) BeginRegistration(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID int `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Err(w, http.StatusBadRequest, respond.CodeBadRequest, "invalid request body")
+		return
+	}
+
+	if !s.authorizeCredentialTarget(r, req.UserID) {
+		respond.Err(w, http.StatusForbidden, respond.CodeForbidden, "cannot register a credential for another user")
+		return
+	}
+
+	user, err := s.users.Get(r.Context(), req.UserID)
+	if err != nil {
+		respond.Err(w, http.StatusNotFound, respond.CodeNotFound, "user not found")
+		return
+	}
+
+	wu := &webauthnUser{user: user, credentials: s.credentials.get(user.ID)}
+	options, sessionData, err := s.webauthn.BeginRegistration(wu)
+	if err != nil {
+		respond.Err(w, http.StatusInternalServerError, respond.CodeInternal, "failed to begin registration")
+		return
+	}
+
+	s.credentials.putSession(sessionKey(user.ID), *sessionData)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(options)
+}
+
+// FinishRegistration completes WebAuthn credential registration for the
+// user named by the userID query parameter. The caller must already hold a
+// session for that user, or be an admin registering a credential on behalf
+// of another account.
+func (s *Service) FinishRegistration(w http.ResponseWriter, r *http.Request) {
+	userID, err := intQueryParam(r, "user_id")
+	if err != nil {
+		respond.Err(w, http.StatusBadRequest, respond.CodeBadRequest, "invalid user_id")
+		return
+	}
+
+	if !s.authorizeCredentialTarget(r, userID) {
+		respond.Err(w, http.StatusForbidden, respond.CodeForbidden, "cannot register a credential for another user")
+		return
+	}
+
+	user, err := s.users.Get(r.Context(), userID)
+	if err != nil {
+		respond.Err(w, http.StatusNotFound, respond.CodeNotFound, "user not found")
+		return
+	}
+
+	sessionData, ok := s.credentials.takeSession(sessionKey(userID))
+	if !ok {
+		respond.Err(w, http.StatusBadRequest, respond.CodeBadRequest, "no registration in progress")
+		return
+	}
+
+	wu := &webauthnUser{user: user, credentials: s.credentials.get(user.ID)}
+	cred, err := s.webauthn.FinishRegistration(wu, sessionData, r)
+	if err != nil {
+		respond.Err(w, http.StatusBadRequest, respond.CodeBadRequest, "registration failed")
+		return
+	}
+
+	s.credentials.add(user.ID, *cred)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BeginLogin starts WebAuthn assertion for the user named by the user_id
+// query parameter.
+func (s *Service) BeginLogin(w http.ResponseWriter, r *http.Request) {
+	userID, err := intQueryParam(r, "user_id")
+	if err != nil {
+		respond.Err(w, http.StatusBadRequest, respond.CodeBadRequest, "invalid user_id")
+		return
+	}
+
+	user, err := s.users.Get(r.Context(), userID)
+	if err != nil {
+		respond.Err(w, http.StatusNotFound, respond.CodeNotFound, "user not found")
+		return
+	}
+
+	wu := &webauthnUser{user: user, credentials: s.credentials.get(user.ID)}
+	options, sessionData, err := s.webauthn.BeginLogin(wu)
+	if err != nil {
+		respond.Err(w, http.StatusInternalServerError, respond.CodeInternal, "failed to begin login")
+		return
+	}
+
+	s.credentials.putSession(sessionKey(user.ID), *sessionData)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(options)
+}
+
+// FinishLogin completes WebAuthn assertion and, on success, issues a
+// session cookie for the user.
+func (s *Service) FinishLogin(w http.ResponseWriter, r *http.Request) {
+	userID, err := intQueryParam(r, "user_id")
+	if err != nil {
+		respond.Err(w, http.StatusBadRequest, respond.CodeBadRequest, "invalid user_id")
+		return
+	}
+
+	user, err := s.users.Get(r.Context(), userID)
+	if err != nil {
+		respond.Err(w, http.StatusNotFound, respond.CodeNotFound, "user not found")
+		return
+	}
+
+	sessionData, ok := s.credentials.takeSession(sessionKey(userID))
+	if !ok {
+		respond.Err(w, http.StatusBadRequest, respond.CodeBadRequest, "no login in progress")
+		return
+	}
+
+	wu := &webauthnUser{user: user, credentials: s.credentials.get(user.ID)}
+	if _, err := s.webauthn.FinishLogin(wu, sessionData, r); err != nil {
+		respond.Err(w, http.StatusUnauthorized, respond.CodeUnauthorized, "login failed")
+		return
+	}
+
+	if err := s.sessions.Issue(w, user.ID); err != nil {
+		respond.Err(w, http.StatusInternalServerError, respond.CodeInternal, "failed to issue session")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Logout revokes the caller's session.
+func (s *Service) Logout(w http.ResponseWriter, r *http.Request) {
+	s.sessions.Revoke(r)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// bootstrapTokenHeader is the header an operator must present to claim the
+// seeded admin's first WebAuthn credential. See isBootstrapTarget.
+const bootstrapTokenHeader = "X-Bootstrap-Token"
+
+// authorizeCredentialTarget reports whether the caller is allowed to attach
+// a new credential to targetUserID: either the caller already holds a
+// session for that same account, or the caller is an admin enrolling a
+// device on another user's behalf. Without this check, registration would
+// let anyone bind a credential to any existing account and log in as them.
+//
+// As a bootstrap exception, it also allows an unauthenticated request
+// through when targetUserID is the sole admin on a store that otherwise has
+// no users and no credential has ever been registered, and the caller
+// presents the configured bootstrap token: otherwise a fresh deployment's
+// seeded admin (see main.seedAdmin) would have an account but no session
+// and no way to ever obtain one.
+func (s *Service) authorizeCredentialTarget(r *http.Request, targetUserID int) bool {
+	if s.isBootstrapTarget(r, targetUserID) {
+		return true
+	}
+
+	callerID, ok := s.sessions.Lookup(r)
+	if !ok {
+		return false
+	}
+	if callerID == targetUserID {
+		return true
+	}
+	caller, err := s.users.Get(r.Context(), callerID)
+	return err == nil && caller.Role == store.RoleAdmin
+}
+
+// isBootstrapTarget reports whether targetUserID is the single seeded admin
+// on a store that has never had a credential registered — the one-time
+// condition a fresh deployment starts in — and r carries the configured
+// bootstrap token. The token requirement keeps this from being a race any
+// network caller can win: without it, whoever reaches the server first
+// would claim the admin account instead of its operator. An unconfigured
+// (empty) token disables the exception entirely.
+func (s *Service) isBootstrapTarget(r *http.Request, targetUserID int) bool {
+	if s.bootstrapToken == "" {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(bootstrapTokenHeader)), []byte(s.bootstrapToken)) != 1 {
+		return false
+	}
+
+	if !s.credentials.empty() {
+		return false
+	}
+
+	_, metadata, err := s.users.List(r.Context(), store.ListParams{Page: 1, PageSize: 1})
+	if err != nil || metadata.TotalRecords != 1 {
+		return false
+	}
+
+	target, err := s.users.Get(r.Context(), targetUserID)
+	return err == nil && target.Role == store.RoleAdmin
+}
+
+func sessionKey(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+func intQueryParam(r *http.Request, name string) (int, error) {
+	return strconv.Atoi(r.URL.Query().Get(name))
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"github.com/webbdan/test-automation/test-api/store"
+)
+
+const testBootstrapToken = "test-bootstrap-token"
+
+func newTestService(t *testing.T, users store.UserStore, sessions *SessionStore) *Service {
+	t.Helper()
+	return newTestServiceWithConfig(t, users, sessions, Config{
+		RPDisplayName: "test",
+		RPID:          "localhost",
+		RPOrigin:      "http://localhost:8080",
+	})
+}
+
+func newTestServiceWithBootstrapToken(t *testing.T, users store.UserStore, sessions *SessionStore) *Service {
+	t.Helper()
+	return newTestServiceWithConfig(t, users, sessions, Config{
+		RPDisplayName:  "test",
+		RPID:           "localhost",
+		RPOrigin:       "http://localhost:8080",
+		BootstrapToken: testBootstrapToken,
+	})
+}
+
+func newTestServiceWithConfig(t *testing.T, users store.UserStore, sessions *SessionStore, cfg Config) *Service {
+	t.Helper()
+	s, err := NewService(cfg, users, sessions)
+	if err != nil {
+		t.Fatalf("constructing service: %v", err)
+	}
+	return s
+}
+
+func TestAuthorizeCredentialTargetRejectsUnauthenticatedCaller(t *testing.T) {
+	users := store.NewMemoryStore()
+	victim, _ := users.Create(context.Background(), store.User{Name: "Victim", Email: "victim@example.com", Role: store.RoleUser})
+	sessions := NewSessionStore()
+	svc := newTestService(t, users, sessions)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin/", nil)
+
+	if svc.authorizeCredentialTarget(req, victim.ID) {
+		t.Fatal("a caller with no session must not be allowed to register a credential for any account")
+	}
+}
+
+func TestAuthorizeCredentialTargetRejectsOtherAccountForNonAdmin(t *testing.T) {
+	users := store.NewMemoryStore()
+	caller, _ := users.Create(context.Background(), store.User{Name: "Caller", Email: "caller@example.com", Role: store.RoleUser})
+	victim, _ := users.Create(context.Background(), store.User{Name: "Victim", Email: "victim@example.com", Role: store.RoleUser})
+	sessions := NewSessionStore()
+	svc := newTestService(t, users, sessions)
+
+	rec := httptest.NewRecorder()
+	if err := sessions.Issue(rec, caller.ID); err != nil {
+		t.Fatalf("issuing session: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin/", nil)
+	req.Header.Set("Cookie", rec.Header().Get("Set-Cookie"))
+
+	if svc.authorizeCredentialTarget(req, victim.ID) {
+		t.Fatal("a non-admin caller must not be allowed to register a credential for another account")
+	}
+	if !svc.authorizeCredentialTarget(req, caller.ID) {
+		t.Fatal("a caller must be allowed to register a credential for their own account")
+	}
+}
+
+func TestAuthorizeCredentialTargetAllowsBootstrapOnSeededAdminWithToken(t *testing.T) {
+	users := store.NewMemoryStore()
+	admin, _ := users.Create(context.Background(), store.User{Name: "Admin", Email: "admin@example.com", Role: store.RoleAdmin})
+	sessions := NewSessionStore()
+	svc := newTestServiceWithBootstrapToken(t, users, sessions)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin/", nil)
+	req.Header.Set(bootstrapTokenHeader, testBootstrapToken)
+
+	if !svc.authorizeCredentialTarget(req, admin.ID) {
+		t.Fatal("the sole seeded admin must be registerable without a session, given the correct bootstrap token, before any credential exists")
+	}
+}
+
+func TestAuthorizeCredentialTargetRejectsBootstrapWithoutToken(t *testing.T) {
+	users := store.NewMemoryStore()
+	admin, _ := users.Create(context.Background(), store.User{Name: "Admin", Email: "admin@example.com", Role: store.RoleAdmin})
+	sessions := NewSessionStore()
+
+	noToken := newTestService(t, users, sessions)
+	reqNoToken := httptest.NewRequest(http.MethodPost, "/auth/register/begin/", nil)
+	if noToken.authorizeCredentialTarget(reqNoToken, admin.ID) {
+		t.Fatal("the bootstrap exception must not apply when no bootstrap token is configured")
+	}
+
+	withToken := newTestServiceWithBootstrapToken(t, users, sessions)
+	reqWrongToken := httptest.NewRequest(http.MethodPost, "/auth/register/begin/", nil)
+	reqWrongToken.Header.Set(bootstrapTokenHeader, "wrong-token")
+	if withToken.authorizeCredentialTarget(reqWrongToken, admin.ID) {
+		t.Fatal("the bootstrap exception must not apply when the caller presents the wrong token")
+	}
+}
+
+func TestAuthorizeCredentialTargetRejectsBootstrapOnceCredentialExists(t *testing.T) {
+	users := store.NewMemoryStore()
+	admin, _ := users.Create(context.Background(), store.User{Name: "Admin", Email: "admin@example.com", Role: store.RoleAdmin})
+	sessions := NewSessionStore()
+	svc := newTestServiceWithBootstrapToken(t, users, sessions)
+	svc.credentials.add(admin.ID, webauthn.Credential{})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin/", nil)
+	req.Header.Set(bootstrapTokenHeader, testBootstrapToken)
+
+	if svc.authorizeCredentialTarget(req, admin.ID) {
+		t.Fatal("the bootstrap exception must not apply once a credential has been registered")
+	}
+}
+
+func TestAuthorizeCredentialTargetAllowsAdminOnOtherAccount(t *testing.T) {
+	users := store.NewMemoryStore()
+	admin, _ := users.Create(context.Background(), store.User{Name: "Admin", Email: "admin@example.com", Role: store.RoleAdmin})
+	victim, _ := users.Create(context.Background(), store.User{Name: "Victim", Email: "victim@example.com", Role: store.RoleUser})
+	sessions := NewSessionStore()
+	svc := newTestService(t, users, sessions)
+
+	rec := httptest.NewRecorder()
+	if err := sessions.Issue(rec, admin.ID); err != nil {
+		t.Fatalf("issuing session: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/register/begin/", nil)
+	req.Header.Set("Cookie", rec.Header().Get("Set-Cookie"))
+
+	if !svc.authorizeCredentialTarget(req, victim.ID) {
+		t.Fatal("an admin caller must be allowed to register a credential on another account's behalf")
+	}
+}
@@ -0,0 +1,290 @@
+// Package config implements the server's hot-reloadable configuration:
+// loading config.yaml, serializing/deserializing it as a whole or by
+// JSONPath, and applying optimistic-concurrency-controlled edits at
+// runtime.
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrFingerprintMismatch is returned by DoLockedAction when the caller's
+// fingerprint no longer matches the current configuration, meaning it was
+// changed concurrently.
+var ErrFingerprintMismatch = errors.New("config: fingerprint mismatch")
+
+// CORS holds the cross-origin settings addCORS applies to every response.
+type CORS struct {
+	AllowedOrigins []string `json:"allowed_origins" yaml:"allowed_origins"`
+	AllowedMethods []string `json:"allowed_methods" yaml:"allowed_methods"`
+	AllowedHeaders []string `json:"allowed_headers" yaml:"allowed_headers"`
+}
+
+// TLS holds the certificate and key paths used when serving over HTTPS.
+// Either may be empty, in which case the server listens over plain HTTP.
+type TLS struct {
+	CertFile string `json:"cert_file" yaml:"cert_file"`
+	KeyFile  string `json:"key_file" yaml:"key_file"`
+}
+
+// Store holds the storage backend selection and its connection string.
+type Store struct {
+	Backend string `json:"backend" yaml:"backend"`
+	DSN     string `json:"dsn" yaml:"dsn"`
+}
+
+// Listing holds tuning knobs for the GET /users/ listing endpoint.
+type Listing struct {
+	MaxPageSize int `json:"max_page_size" yaml:"max_page_size"`
+}
+
+// Bootstrap names the admin account the server seeds on startup when the
+// configured store has no users yet, so a fresh deployment has a way in.
+// Leaving AdminEmail empty disables seeding. Token gates registering the
+// seeded admin's first WebAuthn credential: the operator must present it
+// via the X-Bootstrap-Token header, so an attacker racing to reach the
+// server first can't claim the admin account. Leaving Token empty disables
+// that registration path entirely, since an unauthenticated route with no
+// shared secret would let anyone who reaches it first claim the account.
+type Bootstrap struct {
+	AdminName  string `json:"admin_name" yaml:"admin_name"`
+	AdminEmail string `json:"admin_email" yaml:"admin_email"`
+	Token      string `json:"token" yaml:"token"`
+}
+
+// Config is the full set of values an operator can retune at runtime.
+type Config struct {
+	ListenAddr string    `json:"listen_addr" yaml:"listen_addr"`
+	CORS       CORS      `json:"cors" yaml:"cors"`
+	Store      Store     `json:"store" yaml:"store"`
+	TLS        TLS       `json:"tls" yaml:"tls"`
+	Listing    Listing   `json:"listing" yaml:"listing"`
+	Bootstrap  Bootstrap `json:"bootstrap" yaml:"bootstrap"`
+}
+
+// ConfigHandler is the interface the server uses to read and mutate
+// configuration, independent of how it is stored or persisted.
+type ConfigHandler interface {
+	MarshalJSON() ([]byte, error)
+	UnmarshalJSON(data []byte) error
+	UnmarshalYAML(data []byte) error
+	MarshalJSONPath(path string) ([]byte, error)
+	UnmarshalJSONPath(path string, data []byte) error
+	Fingerprint() string
+	DoLockedAction(fingerprint string, cb func(ConfigHandler) error) error
+}
+
+// Handler is the concrete, file-backed ConfigHandler. It is safe for
+// concurrent use.
+type Handler struct {
+	mu   sync.RWMutex
+	path string
+	cfg  Config
+}
+
+// Load reads and parses path (YAML) into a new Handler.
+func Load(path string) (*Handler, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: reading %s: %w", path, err)
+	}
+
+	h := &Handler{path: path}
+	if err := h.UnmarshalYAML(data); err != nil {
+		return nil, fmt.Errorf("config: parsing %s: %w", path, err)
+	}
+	return h, nil
+}
+
+// Reload re-reads the handler's backing file and replaces its state.
+func (h *Handler) Reload() error {
+	data, err := os.ReadFile(h.path)
+	if err != nil {
+		return fmt.Errorf("config: reloading %s: %w", h.path, err)
+	}
+	return h.UnmarshalYAML(data)
+}
+
+// Snapshot returns a copy of the current configuration.
+func (h *Handler) Snapshot() Config {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.cfg
+}
+
+func (h *Handler) MarshalJSON() ([]byte, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return json.Marshal(h.cfg)
+}
+
+func (h *Handler) UnmarshalJSON(data []byte) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.unmarshalJSONLocked(data)
+}
+
+func (h *Handler) unmarshalJSONLocked(data []byte) error {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+	h.cfg = cfg
+	return nil
+}
+
+func (h *Handler) UnmarshalYAML(data []byte) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+	h.cfg = cfg
+	return nil
+}
+
+// MarshalJSONPath returns the JSON-encoded value at path within the
+// current configuration. See jsonpathGet for the supported path syntax.
+func (h *Handler) MarshalJSONPath(path string) ([]byte, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.marshalJSONPathLocked(path)
+}
+
+func (h *Handler) marshalJSONPathLocked(path string) ([]byte, error) {
+	root, err := toMap(h.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := jsonpathGet(root, path)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// UnmarshalJSONPath decodes data and sets it at path within the current
+// configuration. See jsonpathSet for the supported path syntax.
+func (h *Handler) UnmarshalJSONPath(path string, data []byte) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.unmarshalJSONPathLocked(path, data)
+}
+
+func (h *Handler) unmarshalJSONPathLocked(path string, data []byte) error {
+	root, err := toMap(h.cfg)
+	if err != nil {
+		return err
+	}
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	if err := jsonpathSet(root, path, value); err != nil {
+		return err
+	}
+
+	return fromMap(root, &h.cfg)
+}
+
+// Fingerprint returns a hash of the current configuration, used to detect
+// concurrent modification in DoLockedAction.
+func (h *Handler) Fingerprint() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return fingerprint(h.cfg)
+}
+
+// DoLockedAction applies cb to h under a single exclusive-lock acquisition
+// that spans both the fingerprint comparison and cb's mutation, so the
+// check and the write are atomic. This gives callers optimistic
+// concurrency control: read a fingerprint, decide on an edit, then apply
+// it only if nothing else changed in between. cb receives a ConfigHandler
+// whose methods operate directly on the already-locked Handler; it must
+// not call back into DoLockedAction.
+func (h *Handler) DoLockedAction(fp string, cb func(ConfigHandler) error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if fp != fingerprint(h.cfg) {
+		return ErrFingerprintMismatch
+	}
+	return cb(&lockedHandler{h: h})
+}
+
+// lockedHandler is the ConfigHandler passed to DoLockedAction's callback.
+// It assumes h.mu is already held by the caller, so it calls the
+// lock-free *Locked variants directly instead of Handler's own
+// (lock-acquiring) methods.
+type lockedHandler struct {
+	h *Handler
+}
+
+func (l *lockedHandler) MarshalJSON() ([]byte, error) { return json.Marshal(l.h.cfg) }
+
+func (l *lockedHandler) UnmarshalJSON(data []byte) error { return l.h.unmarshalJSONLocked(data) }
+
+func (l *lockedHandler) UnmarshalYAML(data []byte) error {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+	l.h.cfg = cfg
+	return nil
+}
+
+func (l *lockedHandler) MarshalJSONPath(path string) ([]byte, error) {
+	return l.h.marshalJSONPathLocked(path)
+}
+
+func (l *lockedHandler) UnmarshalJSONPath(path string, data []byte) error {
+	return l.h.unmarshalJSONPathLocked(path, data)
+}
+
+func (l *lockedHandler) Fingerprint() string { return fingerprint(l.h.cfg) }
+
+func (l *lockedHandler) DoLockedAction(fp string, cb func(ConfigHandler) error) error {
+	return errors.New("config: DoLockedAction may not be called reentrantly")
+}
+
+func fingerprint(cfg Config) string {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func toMap(cfg Config) (map[string]any, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func fromMap(m map[string]any, cfg *Config) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, cfg)
+}
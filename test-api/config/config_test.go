@@ -0,0 +1,64 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	h := &Handler{}
+	if err := h.UnmarshalYAML([]byte("listen_addr: \":8080\"\n")); err != nil {
+		t.Fatalf("loading test config: %v", err)
+	}
+	return h
+}
+
+// TestDoLockedActionRejectsConcurrentSameFingerprint ensures only one of two
+// concurrent DoLockedAction calls sharing the same (currently valid)
+// fingerprint can succeed; the other must see ErrFingerprintMismatch rather
+// than both applying their edit.
+func TestDoLockedActionRejectsConcurrentSameFingerprint(t *testing.T) {
+	h := newTestHandler(t)
+	fp := h.Fingerprint()
+
+	var succeeded int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			err := h.DoLockedAction(fp, func(ch ConfigHandler) error {
+				return ch.UnmarshalJSONPath("listen_addr", []byte(`":9090"`))
+			})
+			if err == nil {
+				atomic.AddInt32(&succeeded, 1)
+			} else if err != ErrFingerprintMismatch {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one caller to apply its edit under OCC, got %d", succeeded)
+	}
+}
+
+func TestDoLockedActionCallbackCannotReenter(t *testing.T) {
+	h := newTestHandler(t)
+	fp := h.Fingerprint()
+
+	err := h.DoLockedAction(fp, func(ch ConfigHandler) error {
+		return ch.DoLockedAction(fp, func(ConfigHandler) error { return nil })
+	})
+	if err == nil {
+		t.Fatal("expected reentrant DoLockedAction to fail instead of deadlocking")
+	}
+}
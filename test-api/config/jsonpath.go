@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonpathGet and jsonpathSet support a deliberately small subset of
+// JSONPath: a dot-separated sequence of object keys, with an optional
+// numeric index for array elements (e.g. "cors.allowed_origins.0"). An
+// empty path refers to the whole document.
+func splitPath(path string) []string {
+	path = strings.TrimPrefix(path, "$.")
+	path = strings.TrimPrefix(path, "$")
+	path = strings.Trim(path, ".")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+func jsonpathGet(root any, path string) (any, error) {
+	segments := splitPath(path)
+	node := root
+	for i, seg := range segments {
+		next, err := index(node, seg)
+		if err != nil {
+			return nil, fmt.Errorf("config: %s: %w", strings.Join(segments[:i+1], "."), err)
+		}
+		node = next
+	}
+	return node, nil
+}
+
+func jsonpathSet(root map[string]any, path string, value any) error {
+	segments := splitPath(path)
+	if len(segments) == 0 {
+		return fmt.Errorf("config: empty path is not a valid edit target")
+	}
+
+	node := any(root)
+	for _, seg := range segments[:len(segments)-1] {
+		next, err := index(node, seg)
+		if err != nil {
+			return fmt.Errorf("config: %s: %w", seg, err)
+		}
+		node = next
+	}
+
+	last := segments[len(segments)-1]
+	switch n := node.(type) {
+	case map[string]any:
+		n[last] = value
+		return nil
+	case []any:
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i >= len(n) {
+			return fmt.Errorf("config: %s: index out of range", last)
+		}
+		n[i] = value
+		return nil
+	default:
+		return fmt.Errorf("config: cannot set a field on a scalar value")
+	}
+}
+
+func index(node any, seg string) (any, error) {
+	switch n := node.(type) {
+	case map[string]any:
+		value, ok := n[seg]
+		if !ok {
+			return nil, fmt.Errorf("no such field %q", seg)
+		}
+		return value, nil
+	case []any:
+		i, err := strconv.Atoi(seg)
+		if err != nil || i < 0 || i >= len(n) {
+			return nil, fmt.Errorf("index %q out of range", seg)
+		}
+		return n[i], nil
+	default:
+		return nil, fmt.Errorf("cannot descend into a scalar value")
+	}
+}
@@ -1,44 +1,224 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"runtime/debug"
 	"strconv"
-	"sync"
-)
+	"strings"
+	"syscall"
+	"time"
 
-type User struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
-}
+	"github.com/webbdan/test-automation/test-api/auth"
+	"github.com/webbdan/test-automation/test-api/config"
+	"github.com/webbdan/test-automation/test-api/respond"
+	"github.com/webbdan/test-automation/test-api/store"
+	"github.com/webbdan/test-automation/test-api/validator"
+)
 
 var (
-	users   = make(map[int]User)
-	nextID  = 1
-	mu      sync.Mutex
+	db    store.UserStore
+	audit store.AuditStore
+	cfg   *config.Handler
 )
 
 func main() {
+	configPath := flag.String("config", envOr("CONFIG_FILE", "config.yaml"), "path to config.yaml")
+	rpID := flag.String("rp-id", envOr("RP_ID", "localhost"), "WebAuthn relying party ID")
+	rpOrigin := flag.String("rp-origin", envOr("RP_ORIGIN", "http://localhost:8080"), "WebAuthn relying party origin")
+	shutdownGrace := flag.Duration("shutdown-grace", 10*time.Second, "time allowed for in-flight requests to finish during shutdown")
+	flag.Parse()
+
+	var err error
+	cfg, err = config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	watchForReload(*configPath)
+
+	snapshot := cfg.Snapshot()
+	db, audit, err = openStore(snapshot.Store.Backend, snapshot.Store.DSN)
+	if err != nil {
+		log.Fatalf("failed to open store: %v", err)
+	}
+
+	if err := seedAdmin(db, snapshot.Bootstrap); err != nil {
+		log.Fatalf("failed to seed bootstrap admin: %v", err)
+	}
+
+	sessions := auth.NewSessionStore()
+	authService, err := auth.NewService(auth.Config{
+		RPDisplayName:  "test-automation",
+		RPID:           *rpID,
+		RPOrigin:       *rpOrigin,
+		BootstrapToken: snapshot.Bootstrap.Token,
+	}, db, sessions)
+	if err != nil {
+		log.Fatalf("failed to configure webauthn: %v", err)
+	}
+	authMW := auth.NewMiddleware(db, audit, sessions)
+
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("GET /users/", getUsers)
-	mux.HandleFunc("POST /users/", createUser)
-	mux.HandleFunc("GET /users/{id}/", getUserByID)
-	mux.HandleFunc("PUT /users/{id}/", updateUser)
-	mux.HandleFunc("DELETE /users/{id}/", deleteUser)
+	mux.Handle("GET /users/", authMW.RequireAuth(authMW.RequireAdmin(http.HandlerFunc(getUsers))))
+	mux.Handle("POST /users/", authMW.RequireAuth(authMW.Audited(http.HandlerFunc(createUser))))
+	mux.Handle("GET /users/{id}/", authMW.RequireAuth(authMW.RequireSelfOrAdmin(http.HandlerFunc(getUserByID))))
+	mux.Handle("PUT /users/{id}/", authMW.RequireAuth(authMW.RequireSelfOrAdmin(http.HandlerFunc(updateUser))))
+	mux.Handle("DELETE /users/{id}/", authMW.RequireAuth(authMW.RequireAdmin(http.HandlerFunc(deleteUser))))
+	mux.Handle("GET /audit/", authMW.RequireAuth(authMW.RequireAdmin(http.HandlerFunc(getAudit))))
+	mux.Handle("GET /config/", authMW.RequireAuth(authMW.RequireAdmin(http.HandlerFunc(getConfig))))
+	mux.Handle("PATCH /config/{jsonpath...}", authMW.RequireAuth(authMW.RequireAdmin(http.HandlerFunc(patchConfig))))
+
+	mux.HandleFunc("POST /auth/register/begin/", authService.BeginRegistration)
+	mux.HandleFunc("POST /auth/register/finish/", authService.FinishRegistration)
+	mux.HandleFunc("POST /auth/login/begin/", authService.BeginLogin)
+	mux.HandleFunc("POST /auth/login/finish/", authService.FinishLogin)
+	mux.HandleFunc("POST /auth/logout/", authService.Logout)
 
-	fmt.Println("Server started on :8080")
-	corsMux := addCORS(mux)
-	http.ListenAndServe(":8080", corsMux)
+	handler := addCORS(timeoutMiddleware(recoverMiddleware(methodNotAllowedMiddleware(mux)), defaultRequestTimeout))
+
+	listenAddr := cfg.Snapshot().ListenAddr
+	if listenAddr == "" {
+		listenAddr = ":8080"
+	}
+
+	srv := &http.Server{
+		Addr:         listenAddr,
+		Handler:      handler,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	tls := cfg.Snapshot().TLS
+	go func() {
+		fmt.Printf("Server started on %s\n", listenAddr)
+		if tls.CertFile != "" && tls.KeyFile != "" {
+			serveErr <- srv.ListenAndServeTLS(tls.CertFile, tls.KeyFile)
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serveErr:
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalf("server stopped: %v", err)
+		}
+	case sig := <-stop:
+		log.Printf("received %s, shutting down", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), *shutdownGrace)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Fatalf("graceful shutdown failed: %v", err)
+		}
+	}
+}
+
+// watchForReload reloads cfg from path whenever the process receives
+// SIGHUP. CORS settings take effect immediately, since addCORS reads cfg
+// on every request. The listen address, TLS paths, and store backend/DSN
+// are only read once at startup (the *http.Server is already bound and
+// db/audit are already opened), so changes to those fields require a
+// restart to take effect.
+func watchForReload(path string) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			if err := cfg.Reload(); err != nil {
+				log.Printf("config reload from %s failed: %v", path, err)
+				continue
+			}
+			log.Printf("config reloaded from %s", path)
+		}
+	}()
 }
 
+// openStore constructs the configured UserStore and AuditStore backends.
+// Restarts only preserve data when a SQL backend is selected; "memory" is
+// kept as the zero-configuration default for local development.
+func openStore(backend, dsn string) (store.UserStore, store.AuditStore, error) {
+	switch backend {
+	case "memory", "":
+		return store.NewMemoryStore(), store.NewMemoryAuditStore(), nil
+	case "sqlite":
+		s, err := store.Open(store.DriverSQLite, dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Audit(), nil
+	case "mysql":
+		s, err := store.Open(store.DriverMySQL, dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Audit(), nil
+	case "postgres":
+		s, err := store.Open(store.DriverPostgres, dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Audit(), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown store backend %q", backend)
+	}
+}
+
+// seedAdmin creates the configured bootstrap admin if, and only if, the
+// store has no users yet. Without this, a fresh deployment has no session
+// to call POST /users/ with and no existing account to register a WebAuthn
+// credential against, leaving it permanently locked out of itself.
+// bootstrap.admin_email left empty disables seeding.
+func seedAdmin(db store.UserStore, bootstrap config.Bootstrap) error {
+	if bootstrap.AdminEmail == "" {
+		return nil
+	}
+
+	_, metadata, err := db.List(context.Background(), store.ListParams{Page: 1, PageSize: 1})
+	if err != nil {
+		return err
+	}
+	if metadata.TotalRecords > 0 {
+		return nil
+	}
+
+	_, err = db.Create(context.Background(), store.User{
+		Name:  bootstrap.AdminName,
+		Email: bootstrap.AdminEmail,
+		Role:  store.RoleAdmin,
+	})
+	if err != nil && err != store.ErrConflict {
+		return err
+	}
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// addCORS reads the CORS settings from cfg on every request, so a SIGHUP
+// reload takes effect without restarting the server.
 func addCORS(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*") // Allow all origins
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		cors := cfg.Snapshot().CORS
+		w.Header().Set("Access-Control-Allow-Origin", strings.Join(cors.AllowedOrigins, ", "))
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(cors.AllowedMethods, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(cors.AllowedHeaders, ", "))
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
@@ -49,103 +229,294 @@ func addCORS(h http.Handler) http.Handler {
 	})
 }
 
+// recoverMiddleware converts a panic anywhere downstream into a logged
+// stack trace and a standard 500 error envelope, instead of killing the
+// connection.
+func recoverMiddleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				log.Printf("panic handling %s %s: %v\n%s", r.Method, r.URL.Path, rec, debug.Stack())
+				respond.Err(w, http.StatusInternalServerError, respond.CodeInternal, "internal server error")
+			}
+		}()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// methodNotAllowedMiddleware rewrites http.ServeMux's built-in 405 response
+// (plain text, emitted when a path matches a registered pattern but the
+// method doesn't) into the standard {"error": {...}} envelope, so 405s
+// look like every other error response instead of the stdlib default.
+func methodNotAllowedMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&methodNotAllowedWriter{ResponseWriter: w}, r)
+	})
+}
+
+type methodNotAllowedWriter struct {
+	http.ResponseWriter
+	intercepted bool
+}
+
+func (mw *methodNotAllowedWriter) WriteHeader(code int) {
+	if code == http.StatusMethodNotAllowed {
+		mw.intercepted = true
+		respond.Err(mw.ResponseWriter, http.StatusMethodNotAllowed, respond.CodeMethodNotAllowed, "method not allowed")
+		return
+	}
+	mw.ResponseWriter.WriteHeader(code)
+}
+
+func (mw *methodNotAllowedWriter) Write(b []byte) (int, error) {
+	if mw.intercepted {
+		return len(b), nil
+	}
+	return mw.ResponseWriter.Write(b)
+}
+
 // Handlers
 func getUsers(w http.ResponseWriter, r *http.Request) {
-	mu.Lock()
-	defer mu.Unlock()
+	params, errs := parseListParams(r)
+	if !errs.Valid() {
+		respond.ValidationErr(w, errs)
+		return
+	}
+
+	userList, metadata, err := db.List(r.Context(), params)
+	if err != nil {
+		respond.Err(w, http.StatusInternalServerError, respond.CodeInternal, "failed to list users")
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, map[string]any{
+		"data":     userList,
+		"metadata": metadata,
+	})
+}
+
+// parseListParams translates the page, page_size, sort, name, and email
+// query parameters into a store.ListParams, accumulating one field ->
+// message entry per invalid parameter rather than stopping at the first.
+func parseListParams(r *http.Request) (store.ListParams, validator.Errors) {
+	q := r.URL.Query()
+	errs := validator.Errors{}
+
+	params := store.ListParams{
+		NameFilter:  q.Get("name"),
+		EmailFilter: q.Get("email"),
+		MaxPageSize: cfg.Snapshot().Listing.MaxPageSize,
+	}
 
-	var userList []User
-	for _, user := range users {
-		userList = append(userList, user)
+	if v := q.Get("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			errs["page"] = fmt.Sprintf("invalid page %q", v)
+		} else {
+			params.Page = page
+		}
+	}
+
+	if v := q.Get("page_size"); v != "" {
+		pageSize, err := strconv.Atoi(v)
+		if err != nil || pageSize < 1 {
+			errs["page_size"] = fmt.Sprintf("invalid page_size %q", v)
+		} else {
+			params.PageSize = pageSize
+		}
+	}
+
+	if v := q.Get("sort"); v != "" {
+		field := v
+		if strings.HasPrefix(field, "-") {
+			params.Descending = true
+			field = field[1:]
+		}
+		switch store.SortField(field) {
+		case store.SortByID, store.SortByName, store.SortByEmail:
+			params.SortField = store.SortField(field)
+		default:
+			errs["sort"] = fmt.Sprintf("unknown sort key %q", v)
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(userList)
+	return params, errs
+}
+
+// decodeStrict decodes body into v, rejecting malformed JSON and any field
+// not present in v's type.
+func decodeStrict(body *http.Request, v any) error {
+	dec := json.NewDecoder(body.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+func validateUser(user store.User) validator.Errors {
+	errs := validator.Errors{}
+	validator.ValidateName(errs, user.Name)
+	validator.ValidateEmail(errs, user.Email)
+	return errs
 }
 
 func createUser(w http.ResponseWriter, r *http.Request) {
-	var user User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	var user store.User
+	if err := decodeStrict(r, &user); err != nil {
+		respond.Err(w, http.StatusBadRequest, respond.CodeBadRequest, "invalid request body")
 		return
 	}
 
-	mu.Lock()
-	user.ID = nextID
-	nextID++
-	users[user.ID] = user
-	mu.Unlock()
+	caller, _ := auth.UserFromContext(r.Context())
+	if caller.Role == store.RoleAdmin {
+		if user.Role == "" {
+			user.Role = store.RoleUser
+		}
+	} else {
+		user.Role = store.RoleUser
+	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(user)
+	if errs := validateUser(user); !errs.Valid() {
+		respond.ValidationErr(w, errs)
+		return
+	}
+
+	created, err := db.Create(r.Context(), user)
+	if err == store.ErrConflict {
+		respond.Err(w, http.StatusConflict, respond.CodeConflict, "email already in use")
+		return
+	} else if err != nil {
+		respond.Err(w, http.StatusInternalServerError, respond.CodeInternal, "failed to create user")
+		return
+	}
+
+	respond.JSON(w, http.StatusCreated, created)
 }
 
 func getUserByID(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		respond.Err(w, http.StatusBadRequest, respond.CodeBadRequest, "invalid user id")
 		return
 	}
 
-	mu.Lock()
-	user, exists := users[id]
-	mu.Unlock()
-
-	if !exists {
-		http.Error(w, "User not found", http.StatusNotFound)
+	user, err := db.Get(r.Context(), id)
+	if err == store.ErrNotFound {
+		respond.Err(w, http.StatusNotFound, respond.CodeNotFound, "user not found")
+		return
+	} else if err != nil {
+		respond.Err(w, http.StatusInternalServerError, respond.CodeInternal, "failed to get user")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user)
+	respond.JSON(w, http.StatusOK, user)
 }
 
 func updateUser(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		respond.Err(w, http.StatusBadRequest, respond.CodeBadRequest, "invalid user id")
 		return
 	}
 
-	var updatedUser User
-	if err := json.NewDecoder(r.Body).Decode(&updatedUser); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	var updatedUser store.User
+	if err := decodeStrict(r, &updatedUser); err != nil {
+		respond.Err(w, http.StatusBadRequest, respond.CodeBadRequest, "invalid request body")
 		return
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
+	if errs := validateUser(updatedUser); !errs.Valid() {
+		respond.ValidationErr(w, errs)
+		return
+	}
 
-	if _, exists := users[id]; !exists {
-		http.Error(w, "User not found", http.StatusNotFound)
+	existing, err := db.Get(r.Context(), id)
+	if err == store.ErrNotFound {
+		respond.Err(w, http.StatusNotFound, respond.CodeNotFound, "user not found")
+		return
+	} else if err != nil {
+		respond.Err(w, http.StatusInternalServerError, respond.CodeInternal, "failed to update user")
 		return
 	}
 
-	updatedUser.ID = id
-	users[id] = updatedUser
+	merged := existing
+	merged.Name = updatedUser.Name
+	merged.Email = updatedUser.Email
+	if caller, _ := auth.UserFromContext(r.Context()); updatedUser.Role != "" && caller.Role == store.RoleAdmin {
+		merged.Role = updatedUser.Role
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(updatedUser)
+	saved, err := db.Update(r.Context(), merged)
+	switch err {
+	case nil:
+		respond.JSON(w, http.StatusOK, saved)
+	case store.ErrNotFound:
+		respond.Err(w, http.StatusNotFound, respond.CodeNotFound, "user not found")
+	case store.ErrConflict:
+		respond.Err(w, http.StatusConflict, respond.CodeConflict, "email already in use")
+	default:
+		respond.Err(w, http.StatusInternalServerError, respond.CodeInternal, "failed to update user")
+	}
 }
 
 func deleteUser(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		respond.Err(w, http.StatusBadRequest, respond.CodeBadRequest, "invalid user id")
 		return
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-
-	if _, exists := users[id]; !exists {
-		http.Error(w, "User not found", http.StatusNotFound)
+	if err := db.Delete(r.Context(), id); err == store.ErrNotFound {
+		respond.Err(w, http.StatusNotFound, respond.CodeNotFound, "user not found")
+		return
+	} else if err != nil {
+		respond.Err(w, http.StatusInternalServerError, respond.CodeInternal, "failed to delete user")
 		return
 	}
 
-	delete(users, id)
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func getAudit(w http.ResponseWriter, r *http.Request) {
+	entries, err := audit.List(r.Context())
+	if err != nil {
+		respond.Err(w, http.StatusInternalServerError, respond.CodeInternal, "failed to list audit log")
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, entries)
+}
+
+func getConfig(w http.ResponseWriter, r *http.Request) {
+	respond.JSON(w, http.StatusOK, cfg.Snapshot())
+}
+
+// patchConfigRequest is the body PATCH /config/{jsonpath} expects: the
+// fingerprint the caller last observed (for optimistic concurrency
+// control) and the new value to set at that path.
+type patchConfigRequest struct {
+	Fingerprint string          `json:"fingerprint"`
+	Value       json.RawMessage `json:"value"`
+}
+
+func patchConfig(w http.ResponseWriter, r *http.Request) {
+	var req patchConfigRequest
+	if err := decodeStrict(r, &req); err != nil {
+		respond.Err(w, http.StatusBadRequest, respond.CodeBadRequest, "invalid request body")
+		return
+	}
+
+	path := strings.ReplaceAll(r.PathValue("jsonpath"), "/", ".")
+	err := cfg.DoLockedAction(req.Fingerprint, func(ch config.ConfigHandler) error {
+		return ch.UnmarshalJSONPath(path, req.Value)
+	})
+	switch {
+	case err == nil:
+		respond.JSON(w, http.StatusOK, cfg.Snapshot())
+	case err == config.ErrFingerprintMismatch:
+		respond.Err(w, http.StatusConflict, respond.CodeConflict, "config changed concurrently; refetch and retry")
+	default:
+		respond.Err(w, http.StatusUnprocessableEntity, respond.CodeValidation, err.Error())
+	}
+}
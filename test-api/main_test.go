@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webbdan/test-automation/test-api/config"
+	"github.com/webbdan/test-automation/test-api/store"
+)
+
+func setupTestState(t *testing.T) {
+	t.Helper()
+	db = store.NewMemoryStore()
+	audit = store.NewMemoryAuditStore()
+	cfg = &config.Handler{}
+	if err := cfg.UnmarshalYAML([]byte("listen_addr: \":8080\"\n")); err != nil {
+		t.Fatalf("loading test config: %v", err)
+	}
+}
+
+func TestCreateUserIgnoresRoleForNonAdmin(t *testing.T) {
+	setupTestState(t)
+
+	body := strings.NewReader(`{"name":"Alice","email":"alice@example.com","role":"admin"}`)
+	req := httptest.NewRequest(http.MethodPost, "/users/", body)
+	rec := httptest.NewRecorder()
+
+	createUser(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	created, err := db.Get(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("fetching created user: %v", err)
+	}
+	if created.Role != store.RoleUser {
+		t.Fatalf("expected a self-registered user to default to role %q, got %q", store.RoleUser, created.Role)
+	}
+}
+
+func TestUpdateUserPreservesRoleForNonAdmin(t *testing.T) {
+	setupTestState(t)
+
+	admin, err := db.Create(context.Background(), store.User{Name: "Root", Email: "root@example.com", Role: store.RoleAdmin})
+	if err != nil {
+		t.Fatalf("seeding admin: %v", err)
+	}
+
+	body := strings.NewReader(`{"name":"Root Renamed","email":"root@example.com","role":"user"}`)
+	req := httptest.NewRequest(http.MethodPut, "/users/1/", body)
+	req.SetPathValue("id", "1")
+	rec := httptest.NewRecorder()
+
+	updateUser(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	saved, err := db.Get(context.Background(), admin.ID)
+	if err != nil {
+		t.Fatalf("fetching updated user: %v", err)
+	}
+	if saved.Role != store.RoleAdmin {
+		t.Fatalf("update without an admin caller must not change role, got %q", saved.Role)
+	}
+	if saved.Name != "Root Renamed" {
+		t.Fatalf("expected name to be updated, got %q", saved.Name)
+	}
+}
+
+func TestTimeoutMiddlewareRecoversHandlerPanics(t *testing.T) {
+	panicky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	handler := timeoutMiddleware(recoverMiddleware(panicky), time.Second)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(rec, req)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler did not return; a panic likely escaped its goroutine")
+	}
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected panic to be recovered into a 500, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,59 @@
+// Package respond provides the JSON response helpers shared by every
+// handler, so that success and error payloads have one consistent shape
+// across the API.
+package respond
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes returned under error.code. These are stable and safe for
+// clients to branch on.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeValidation       = "validation_failed"
+	CodeUnavailable      = "unavailable"
+	CodeInternal         = "internal_error"
+)
+
+// errorBody is the shape of the top-level "error" field.
+type errorBody struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// envelope wraps errorBody under "error", matching the {"error": {...}}
+// contract every failing response follows.
+type envelope struct {
+	Error errorBody `json:"error"`
+}
+
+// JSON writes v as the response body with the given status code.
+func JSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Err writes a {"error": {...}} envelope with the given status, code, and
+// message.
+func Err(w http.ResponseWriter, status int, code, message string) {
+	JSON(w, status, envelope{Error: errorBody{Code: code, Message: message}})
+}
+
+// ValidationErr writes a 422 {"error": {...}} envelope carrying the
+// field -> message map produced by the validator package.
+func ValidationErr(w http.ResponseWriter, fields map[string]string) {
+	JSON(w, http.StatusUnprocessableEntity, envelope{Error: errorBody{
+		Code:    CodeValidation,
+		Message: "validation failed",
+		Fields:  fields,
+	}})
+}
@@ -0,0 +1,288 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Driver identifies which SQL dialect a GormStore should use.
+type Driver string
+
+const (
+	DriverSQLite   Driver = "sqlite"
+	DriverMySQL    Driver = "mysql"
+	DriverPostgres Driver = "postgres"
+)
+
+// GormStore is a UserStore backed by GORM. It supports SQLite, MySQL, and
+// Postgres; the dialect and connection string are supplied by the caller
+// (normally sourced from configuration) and the schema is migrated on open.
+type GormStore struct {
+	db *gorm.DB
+}
+
+// gormUser is the row shape persisted by GORM; it mirrors User but carries
+// the gorm.Model tags needed for migration.
+type gormUser struct {
+	ID    int    `gorm:"primaryKey"`
+	Name  string `gorm:"size:100;not null"`
+	Email string `gorm:"size:255;uniqueIndex;not null"`
+	Role  string `gorm:"size:20;not null;default:user"`
+}
+
+func (gormUser) TableName() string { return "users" }
+
+// gormAuditEntry is the row shape persisted for AuditEntry.
+type gormAuditEntry struct {
+	ID        int `gorm:"primaryKey"`
+	Timestamp time.Time
+	UserID    int
+	IP        string `gorm:"size:45"`
+	Action    string `gorm:"size:100"`
+	Outcome   string `gorm:"size:20"`
+}
+
+func (gormAuditEntry) TableName() string { return "audit_log" }
+
+// Open connects to the database identified by driver and dsn and migrates
+// the schema. For DriverSQLite, dsn is a file path (or ":memory:").
+func Open(driver Driver, dsn string) (*GormStore, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case DriverSQLite:
+		dialector = sqlite.Open(dsn)
+	case DriverMySQL:
+		dialector = mysql.Open(dsn)
+	case DriverPostgres:
+		dialector = postgres.Open(dsn)
+	default:
+		return nil, errUnsupportedDriver(driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.AutoMigrate(&gormUser{}, &gormAuditEntry{}); err != nil {
+		return nil, err
+	}
+
+	return &GormStore{db: db}, nil
+}
+
+func errUnsupportedDriver(d Driver) error {
+	return &unsupportedDriverError{driver: d}
+}
+
+type unsupportedDriverError struct {
+	driver Driver
+}
+
+func (e *unsupportedDriverError) Error() string {
+	return "store: unsupported driver " + string(e.driver)
+}
+
+func toUser(r gormUser) User {
+	return User{ID: r.ID, Name: r.Name, Email: r.Email, Role: Role(r.Role)}
+}
+
+func toGormUser(u User) gormUser {
+	return gormUser{ID: u.ID, Name: u.Name, Email: u.Email, Role: string(u.Role)}
+}
+
+// List pushes filtering, sorting, and pagination into the query rather than
+// loading the whole table and reusing applyListParams (as MemoryStore
+// does): at any real scale, fetching every row just to discard most of them
+// in Go defeats the point of a SQL-backed store.
+func (s *GormStore) List(ctx context.Context, params ListParams) ([]User, ListMetadata, error) {
+	filtered := func() *gorm.DB {
+		q := s.db.WithContext(ctx).Model(&gormUser{})
+		if params.NameFilter != "" {
+			q = q.Where("LOWER(name) LIKE ?", "%"+strings.ToLower(params.NameFilter)+"%")
+		}
+		if params.EmailFilter != "" {
+			q = q.Where("LOWER(email) LIKE ?", "%"+strings.ToLower(params.EmailFilter)+"%")
+		}
+		return q
+	}
+
+	var total int64
+	if err := filtered().Count(&total).Error; err != nil {
+		return nil, ListMetadata{}, err
+	}
+
+	maxPageSize := params.MaxPageSize
+	if maxPageSize <= 0 {
+		maxPageSize = fallbackMaxPageSize
+	}
+	pageSize := params.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	page := params.Page
+	if page <= 0 {
+		page = 1
+	}
+
+	lastPage := (int(total) + pageSize - 1) / pageSize
+	if lastPage == 0 {
+		lastPage = 1
+	}
+	metadata := ListMetadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		TotalRecords: int(total),
+		FirstPage:    1,
+		LastPage:     lastPage,
+	}
+
+	orderColumn := "id"
+	switch params.SortField {
+	case SortByName:
+		orderColumn = "name"
+	case SortByEmail:
+		orderColumn = "email"
+	}
+	direction := "ASC"
+	if params.Descending {
+		direction = "DESC"
+	}
+
+	var rows []gormUser
+	err := filtered().
+		Order(orderColumn + " " + direction).
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&rows).Error
+	if err != nil {
+		return nil, ListMetadata{}, err
+	}
+
+	users := make([]User, 0, len(rows))
+	for _, r := range rows {
+		users = append(users, toUser(r))
+	}
+	return users, metadata, nil
+}
+
+func (s *GormStore) Get(ctx context.Context, id int) (User, error) {
+	var row gormUser
+	if err := s.db.WithContext(ctx).First(&row, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return User{}, ErrNotFound
+		}
+		return User{}, err
+	}
+	return toUser(row), nil
+}
+
+func (s *GormStore) Create(ctx context.Context, user User) (User, error) {
+	row := toGormUser(user)
+	if err := s.db.WithContext(ctx).Create(&row).Error; err != nil {
+		if isUniqueViolation(err) {
+			return User{}, ErrConflict
+		}
+		return User{}, err
+	}
+	return toUser(row), nil
+}
+
+// Update checks the row exists via a separate Get up front rather than
+// inferring it solely from RowsAffected: under MySQL, without
+// clientFoundRows=true, RowsAffected counts rows changed, not rows
+// matched, so resubmitting identical values for an existing user would
+// otherwise report ErrNotFound. If RowsAffected is still zero after the
+// write, that up-front Get can't rule out a concurrent delete racing the
+// Updates call, so it re-checks with another Get rather than assuming the
+// zero count was just a same-value no-op.
+func (s *GormStore) Update(ctx context.Context, user User) (User, error) {
+	if _, err := s.Get(ctx, user.ID); err != nil {
+		return User{}, err
+	}
+
+	row := toGormUser(user)
+	result := s.db.WithContext(ctx).Model(&gormUser{}).Where("id = ?", row.ID).Updates(row)
+	if result.Error != nil {
+		if isUniqueViolation(result.Error) {
+			return User{}, ErrConflict
+		}
+		return User{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.Get(ctx, row.ID); err != nil {
+			return User{}, err
+		}
+	}
+	return toUser(row), nil
+}
+
+// isUniqueViolation reports whether err looks like a unique constraint
+// failure. GORM does not normalize this across dialects, so we match on
+// the substrings each driver is known to emit.
+func isUniqueViolation(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unique") || strings.Contains(msg, "duplicate")
+}
+
+func (s *GormStore) Delete(ctx context.Context, id int) error {
+	result := s.db.WithContext(ctx).Delete(&gormUser{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Audit returns an AuditStore backed by the same database connection, so
+// user records and audit entries stay consistent under one DSN.
+func (s *GormStore) Audit() *GormAuditStore {
+	return &GormAuditStore{db: s.db}
+}
+
+// GormAuditStore is an AuditStore backed by GORM.
+type GormAuditStore struct {
+	db *gorm.DB
+}
+
+func (s *GormAuditStore) Record(ctx context.Context, entry AuditEntry) error {
+	row := gormAuditEntry{
+		Timestamp: entry.Timestamp,
+		UserID:    entry.UserID,
+		IP:        entry.IP,
+		Action:    entry.Action,
+		Outcome:   entry.Outcome,
+	}
+	return s.db.WithContext(ctx).Create(&row).Error
+}
+
+func (s *GormAuditStore) List(ctx context.Context) ([]AuditEntry, error) {
+	var rows []gormAuditEntry
+	if err := s.db.WithContext(ctx).Order("timestamp").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]AuditEntry, 0, len(rows))
+	for _, r := range rows {
+		entries = append(entries, AuditEntry{
+			ID:        r.ID,
+			Timestamp: r.Timestamp,
+			UserID:    r.UserID,
+			IP:        r.IP,
+			Action:    r.Action,
+			Outcome:   r.Outcome,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	defaultPageSize = 20
+
+	// fallbackMaxPageSize caps PageSize when ListParams.MaxPageSize is
+	// unset, e.g. for callers that don't thread a configured cap through.
+	fallbackMaxPageSize = 100
+)
+
+// applyListParams filters, sorts, and paginates an in-memory slice of
+// users. Both MemoryStore and GormStore share this so that listing
+// behaves identically regardless of backend.
+func applyListParams(users []User, params ListParams) ([]User, ListMetadata) {
+	filtered := make([]User, 0, len(users))
+	for _, u := range users {
+		if params.NameFilter != "" && !strings.Contains(strings.ToLower(u.Name), strings.ToLower(params.NameFilter)) {
+			continue
+		}
+		if params.EmailFilter != "" && !strings.Contains(strings.ToLower(u.Email), strings.ToLower(params.EmailFilter)) {
+			continue
+		}
+		filtered = append(filtered, u)
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		var less bool
+		switch params.SortField {
+		case SortByName:
+			less = filtered[i].Name < filtered[j].Name
+		case SortByEmail:
+			less = filtered[i].Email < filtered[j].Email
+		default:
+			less = filtered[i].ID < filtered[j].ID
+		}
+		if params.Descending {
+			return !less
+		}
+		return less
+	})
+
+	maxPageSize := params.MaxPageSize
+	if maxPageSize <= 0 {
+		maxPageSize = fallbackMaxPageSize
+	}
+
+	pageSize := params.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	page := params.Page
+	if page <= 0 {
+		page = 1
+	}
+
+	total := len(filtered)
+	lastPage := (total + pageSize - 1) / pageSize
+	if lastPage == 0 {
+		lastPage = 1
+	}
+
+	metadata := ListMetadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		TotalRecords: total,
+		FirstPage:    1,
+		LastPage:     lastPage,
+	}
+
+	start := (page - 1) * pageSize
+	if start >= total {
+		return []User{}, metadata
+	}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+	return filtered[start:end], metadata
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is an in-memory UserStore. It is the default backend and is
+// primarily useful for local development and tests; data does not survive a
+// restart.
+type MemoryStore struct {
+	mu     sync.Mutex
+	users  map[int]User
+	nextID int
+}
+
+// NewMemoryStore returns an empty MemoryStore ready for use.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:  make(map[int]User),
+		nextID: 1,
+	}
+}
+
+func (s *MemoryStore) List(ctx context.Context, params ListParams) ([]User, ListMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+
+	page, metadata := applyListParams(users, params)
+	return page, metadata, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id int) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, exists := s.users[id]
+	if !exists {
+		return User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *MemoryStore) Create(ctx context.Context, user User) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(user.Email, 0) {
+		return User{}, ErrConflict
+	}
+
+	user.ID = s.nextID
+	s.nextID++
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, user User) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[user.ID]; !exists {
+		return User{}, ErrNotFound
+	}
+	if s.emailTaken(user.Email, user.ID) {
+		return User{}, ErrConflict
+	}
+	s.users[user.ID] = user
+	return user, nil
+}
+
+// emailTaken reports whether email already belongs to a user other than
+// excludeID. Callers must hold s.mu.
+func (s *MemoryStore) emailTaken(email string, excludeID int) bool {
+	for _, u := range s.users {
+		if u.Email == email && u.ID != excludeID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[id]; !exists {
+		return ErrNotFound
+	}
+	delete(s.users, id)
+	return nil
+}
+
+// MemoryAuditStore is an in-memory AuditStore, used alongside MemoryStore
+// for local development.
+type MemoryAuditStore struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+	nextID  int
+}
+
+// NewMemoryAuditStore returns an empty MemoryAuditStore ready for use.
+func NewMemoryAuditStore() *MemoryAuditStore {
+	return &MemoryAuditStore{nextID: 1}
+}
+
+func (s *MemoryAuditStore) Record(ctx context.Context, entry AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.ID = s.nextID
+	s.nextID++
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *MemoryAuditStore) List(ctx context.Context) ([]AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]AuditEntry, len(s.entries))
+	copy(entries, s.entries)
+	return entries, nil
+}
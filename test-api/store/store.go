@@ -0,0 +1,93 @@
+// Package store defines the persistence interface for users and the
+// implementations the server can be configured to use.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by UserStore implementations when a user lookup,
+// update, or delete targets an ID that does not exist.
+var ErrNotFound = errors.New("store: user not found")
+
+// ErrConflict is returned by UserStore.Create and Update when the email
+// being written is already taken by a different user.
+var ErrConflict = errors.New("store: email already in use")
+
+// Role identifies what a user is authorized to do.
+type Role string
+
+const (
+	RoleAdmin Role = "admin"
+	RoleUser  Role = "user"
+)
+
+// User is the persisted representation of a user record.
+type User struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Role  Role   `json:"role"`
+}
+
+// AuditEntry records the outcome of a single mutating request.
+type AuditEntry struct {
+	ID        int       `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	UserID    int       `json:"user_id"`
+	IP        string    `json:"ip"`
+	Action    string    `json:"action"`
+	Outcome   string    `json:"outcome"`
+}
+
+// AuditStore persists and retrieves audit log entries.
+type AuditStore interface {
+	Record(ctx context.Context, entry AuditEntry) error
+	List(ctx context.Context) ([]AuditEntry, error)
+}
+
+// SortField identifies a User field a listing can be sorted by.
+type SortField string
+
+const (
+	SortByID    SortField = "id"
+	SortByName  SortField = "name"
+	SortByEmail SortField = "email"
+)
+
+// ListParams controls pagination, sorting, and filtering of List results.
+// Page and PageSize are both 1-indexed/positive; SortField's zero value
+// means unsorted (store-defined order), and Descending reverses it.
+// MaxPageSize caps PageSize; zero means the store's own default cap.
+type ListParams struct {
+	Page        int
+	PageSize    int
+	MaxPageSize int
+	SortField   SortField
+	Descending  bool
+	NameFilter  string
+	EmailFilter string
+}
+
+// ListMetadata describes the page returned by a List call relative to the
+// full, filtered result set.
+type ListMetadata struct {
+	CurrentPage  int `json:"current_page"`
+	PageSize     int `json:"page_size"`
+	TotalRecords int `json:"total_records"`
+	FirstPage    int `json:"first_page"`
+	LastPage     int `json:"last_page"`
+}
+
+// UserStore is implemented by every storage backend the server supports.
+// Handlers depend only on this interface so the backend can be swapped via
+// configuration without touching request handling code.
+type UserStore interface {
+	List(ctx context.Context, params ListParams) ([]User, ListMetadata, error)
+	Get(ctx context.Context, id int) (User, error)
+	Create(ctx context.Context, user User) (User, error)
+	Update(ctx context.Context, user User) (User, error)
+	Delete(ctx context.Context, id int) error
+}
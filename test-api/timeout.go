@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/webbdan/test-automation/test-api/respond"
+)
+
+const defaultRequestTimeout = 5 * time.Second
+
+// timeoutWriter wraps an http.ResponseWriter so that writes are dropped
+// once the request has already been reported as timed out, avoiding a
+// "superfluous WriteHeader" race between the handler goroutine and the
+// timeout response.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	w        http.ResponseWriter
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.w.Header() }
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	tw.w.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return len(b), nil
+	}
+	return tw.w.Write(b)
+}
+
+// timeoutMiddleware enforces a per-request deadline, canceling the
+// request's context so downstream UserStore calls can abandon in-flight
+// work, and returning the standard error envelope with 503 if the handler
+// hasn't finished by the deadline.
+func timeoutMiddleware(next http.Handler, d time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+
+		tw := &timeoutWriter{w: w}
+		done := make(chan struct{})
+		go func() {
+			next.ServeHTTP(tw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			tw.mu.Lock()
+			tw.timedOut = true
+			tw.mu.Unlock()
+			respond.Err(w, http.StatusServiceUnavailable, respond.CodeUnavailable, "request timed out")
+		}
+	})
+}
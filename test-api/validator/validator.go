@@ -0,0 +1,40 @@
+// Package validator holds field-level validation rules shared by the user
+// handlers.
+package validator
+
+import "regexp"
+
+// emailPattern is a pragmatic approximation of RFC 5322; it is deliberately
+// simpler than the full grammar, which accepts addresses no mail system in
+// practice does.
+var emailPattern = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+
+const maxNameLength = 100
+
+// Errors accumulates field -> message validation failures.
+type Errors map[string]string
+
+// Valid reports whether no errors have been recorded.
+func (e Errors) Valid() bool {
+	return len(e) == 0
+}
+
+// ValidateName checks that name is non-empty and within the length limit,
+// recording a failure under "name" if not.
+func ValidateName(errs Errors, name string) {
+	switch {
+	case name == "":
+		errs["name"] = "must not be empty"
+	case len(name) > maxNameLength:
+		errs["name"] = "must not exceed 100 characters"
+	}
+}
+
+// ValidateEmail checks that email is well-formed, recording a failure under
+// "email" if not. Uniqueness is a storage-layer concern and is not checked
+// here.
+func ValidateEmail(errs Errors, email string) {
+	if !emailPattern.MatchString(email) {
+		errs["email"] = "must be a valid email address"
+	}
+}